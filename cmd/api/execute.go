@@ -1,28 +1,44 @@
 package main
 
 import (
-	"archive/tar"
-	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
+	"io"
 	"net/http"
-
-	"fmt"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/gorilla/websocket"
+
+	"github.com/jelly-afk/sandboxed/runtimes"
 )
 
+// defaultLanguage is used when a request omits "language", so the original
+// single-language protocol keeps working unchanged.
+const defaultLanguage = "go"
+
+// maxMessageSize bounds the raw WebSocket message gorilla/websocket will
+// read before it closes the connection, so a single oversized payload can't
+// exhaust the API host's memory before any container resource limit ever
+// comes into play. base64 inflates size by about a third, so this leaves
+// headroom above maxUploadBytes for the tar_b64 path.
+const maxMessageSize = 2 * maxUploadBytes
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
-		return true 
+		return true
 	},
 }
 
 func (app *application) executeHandler(w http.ResponseWriter, r *http.Request) {
+	app.inFlight.Add(1)
+	defer app.inFlight.Done()
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -31,9 +47,14 @@ func (app *application) executeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer conn.Close()
+	conn.SetReadLimit(maxMessageSize)
 
 	var input struct {
-		Text string `json:"text"`
+		Text     string       `json:"text"`
+		Language string       `json:"language"`
+		Files    []uploadFile `json:"files"`
+		TarB64   string       `json:"tar_b64"`
+		TTY      bool         `json:"tty"`
 	}
 	err = conn.ReadJSON(&input)
 	if err != nil {
@@ -41,6 +62,16 @@ func (app *application) executeHandler(w http.ResponseWriter, r *http.Request) {
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusBadRequest)))
 		return
 	}
+	if input.Language == "" {
+		input.Language = defaultLanguage
+	}
+
+	rt, err := app.runtimes.Lookup(input.Language)
+	if err != nil {
+		app.logger.Printf("[ERROR] %v", err)
+		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusBadRequest)))
+		return
+	}
 
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.43"))
 	if err != nil {
@@ -50,102 +81,122 @@ func (app *application) executeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer cli.Close()
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), rt.Timeout())
+	defer cancel()
 
-	resp, err := cli.ContainerCreate(ctx, &container.Config{
-		Image:        "golang:1.21",
-		Cmd:          []string{"go", "run", "main.go"},
-		WorkingDir:   "/app",
-		Tty:          true,
-		AttachStdout: true,
-		AttachStderr: true,
-	}, &container.HostConfig{
-		AutoRemove: true,
-	}, nil, nil, "")
+	runnerID, err := app.pool.Acquire(ctx, rt.Image())
 	if err != nil {
-		app.logger.Printf("[ERROR] Failed to create container: %v", err)
+		app.logger.Printf("[ERROR] Failed to acquire runner: %v", err)
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
 		return
 	}
+	defer app.pool.Release(rt.Image(), runnerID)
 
-	defer func() {
-		_ = cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
-	}()
-
-	var buf bytes.Buffer
-	tw := tar.NewWriter(&buf)
-	defer tw.Close()
-
-	if err := tw.WriteHeader(&tar.Header{
-		Name: "main.go",
-		Mode: 0644,
-		Size: int64(len(input.Text)),
-	}); err != nil {
-		app.logger.Printf("[ERROR] Failed to write tar header: %v", err)
-		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
-		return
+	var buf *bytes.Buffer
+	switch {
+	case input.TarB64 != "":
+		buf, err = tarFromBase64(input.TarB64)
+		if err != nil {
+			app.logger.Printf("[ERROR] Invalid tar upload: %v", err)
+			conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusBadRequest)))
+			return
+		}
+	case len(input.Files) > 0:
+		files, ferr := filesFromUpload(input.Files)
+		if ferr != nil {
+			app.logger.Printf("[ERROR] Invalid file upload: %v", ferr)
+			conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusBadRequest)))
+			return
+		}
+		buf, err = buildTar(files)
+	default:
+		buf, err = buildTar(rt.Files(runtimes.Payload{Text: input.Text}))
 	}
-
-	if _, err := tw.Write([]byte(input.Text)); err != nil {
-		app.logger.Printf("[ERROR] Failed to write file to tar: %v", err)
+	if err != nil {
+		app.logger.Printf("[ERROR] Failed to build tar: %v", err)
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
 		return
 	}
 
-	if err := tw.Close(); err != nil {
-		app.logger.Printf("[ERROR] Failed to close tar writer: %v", err)
+	if err := cli.CopyToContainer(ctx, runnerID, rt.WorkDir(), buf, container.CopyToContainerOptions{}); err != nil {
+		app.logger.Printf("[ERROR] Failed to copy files to container: %v", err)
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
 		return
 	}
 
-	if err := cli.CopyToContainer(ctx, resp.ID, "/app", &buf, container.CopyToContainerOptions{}); err != nil {
-		app.logger.Printf("[ERROR] Failed to copy files to container: %v", err)
+	execResp, err := cli.ContainerExecCreate(ctx, runnerID, container.ExecOptions{
+		Cmd:          rt.Command(),
+		WorkingDir:   rt.WorkDir(),
+		Tty:          input.TTY,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		app.logger.Printf("[ERROR] Failed to create exec: %v", err)
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
 		return
 	}
 
-	if err := cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		app.logger.Printf("[ERROR] Failed to start container: %v", err)
+	attachResp, err := cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{Tty: input.TTY})
+	if err != nil {
+		app.logger.Printf("[ERROR] Failed to attach to exec: %v", err)
 		conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
 		return
 	}
-	fmt.Println("container started")
+	defer attachResp.Close()
 
-	done := make(chan struct{})
+	go forwardStdin(ctx, conn, cli, execResp.ID, attachResp.Conn, app.logger.Printf)
 
+	// If the request's timeout fires, the runner is on loan to nobody else,
+	// so kill it outright rather than waiting for the sandboxed process to
+	// notice; Release still tears the container down and replaces it.
 	go func() {
-		reader, err := cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{
-			ShowStdout: true,
-			ShowStderr: true,
-			Follow:     true,
-			Timestamps: false,
-		})
-		if err != nil {
-			app.logger.Printf("[ERROR] Failed to get container logs: %v", err)
-			conn.WriteMessage(websocket.CloseMessage, []byte(http.StatusText(http.StatusInternalServerError)))
+		<-ctx.Done()
+		if ctx.Err() != context.DeadlineExceeded {
 			return
 		}
-		defer reader.Close()
-		scanner := bufio.NewScanner(reader)
-		for scanner.Scan() {
-			if err := conn.WriteJSON(scanner.Text()); err != nil {
-				app.logger.Printf("[ERROR] Failed to write to websocket: %v", err)
-				return
-			}
+		app.logger.Printf("[ERROR] execution exceeded %s, killing runner %s", rt.Timeout(), runnerID)
+		killCtx, killCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer killCancel()
+		if err := cli.ContainerKill(killCtx, runnerID, "SIGKILL"); err != nil {
+			app.logger.Printf("[ERROR] Failed to kill timed-out runner %s: %v", runnerID, err)
 		}
-		close(done)
 	}()
 
-	go func() {
-		statusCh, errCh := cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
-		select {
-		case err := <-errCh:
-			if err != nil {
-				app.logger.Printf("[ERROR] Container wait error: %v", err)
-			}
-		case <-statusCh:
+	fw := &frameWriter{conn: conn}
+	if input.TTY {
+		// A TTY merges stdout and stderr, so there's nothing left to
+		// demultiplex: copy the combined stream through as "stdout".
+		stdout := newCoalescingWriter("stdout", fw)
+		if _, err := io.Copy(stdout, attachResp.Reader); err != nil {
+			app.logger.Printf("[ERROR] Failed to copy exec output: %v", err)
 		}
-	}()
+		stdout.Close()
+	} else {
+		stdout := newCoalescingWriter("stdout", fw)
+		stderr := newCoalescingWriter("stderr", fw)
+		if _, err := stdcopy.StdCopy(stdout, stderr, attachResp.Reader); err != nil {
+			app.logger.Printf("[ERROR] Failed to demultiplex exec output: %v", err)
+		}
+		stdout.Close()
+		stderr.Close()
+	}
 
-	<-done
+	if ctx.Err() == context.DeadlineExceeded {
+		data := base64.StdEncoding.EncodeToString([]byte("execution timed out"))
+		if err := fw.send(streamFrame{Stream: "error", Data: data}); err != nil {
+			app.logger.Printf("[ERROR] Failed to write to websocket: %v", err)
+		}
+		return
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		app.logger.Printf("[ERROR] Failed to inspect exec: %v", err)
+		return
+	}
+	if err := fw.send(streamFrame{Stream: "exit", Code: inspect.ExitCode}); err != nil {
+		app.logger.Printf("[ERROR] Failed to write to websocket: %v", err)
+	}
 }
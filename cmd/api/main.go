@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/docker/docker/client"
+
+	"github.com/jelly-afk/sandboxed/pool"
+	"github.com/jelly-afk/sandboxed/runtimes"
 )
 
 const version = "1.0.0"
@@ -15,11 +25,32 @@ const version = "1.0.0"
 type config struct {
 	port int
 	env  string
+
+	poolSize        int
+	poolImages      string
+	poolMaxLifetime time.Duration
+
+	runtimesConfig string
+
+	sandboxMemory         int64
+	sandboxNanoCPUs       int64
+	sandboxPidsLimit      int64
+	sandboxNetworkMode    string
+	sandboxReadonlyRootfs bool
+	seccompProfile        string
 }
 
 type application struct {
-	config config
-	logger *log.Logger
+	config   config
+	logger   *log.Logger
+	pool     *pool.EnvironmentManager
+	runtimes *runtimes.Registry
+
+	// inFlight tracks executeHandler calls still running. srv.Shutdown does
+	// not wait for hijacked connections such as the WebSockets /v1/execute
+	// upgrades to, so shutdown waits on this directly before tearing down
+	// the pool those executions are still holding runners from.
+	inFlight sync.WaitGroup
 }
 
 // errorResponse is a helper for sending JSON-formatted error messages
@@ -38,11 +69,97 @@ func main() {
 	var cfg config
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	flag.IntVar(&cfg.poolSize, "pool-size", 3, "number of warm containers to keep ready per pooled image")
+	flag.StringVar(&cfg.poolImages, "pool-images", "", "comma-separated list of images to prewarm (defaults to every registered runtime's image)")
+	flag.DurationVar(&cfg.poolMaxLifetime, "pool-max-lifetime", 10*time.Minute, "maximum age of a warm container before it is recycled")
+	flag.StringVar(&cfg.runtimesConfig, "runtimes-config", "", "path to a YAML file registering additional runtimes")
+	flag.Int64Var(&cfg.sandboxMemory, "sandbox-memory", 256*1024*1024, "default memory limit in bytes for sandboxed containers")
+	flag.Int64Var(&cfg.sandboxNanoCPUs, "sandbox-nano-cpus", 1_000_000_000, "default CPU quota in nanocpus for sandboxed containers")
+	flag.Int64Var(&cfg.sandboxPidsLimit, "sandbox-pids-limit", 64, "default pids limit for sandboxed containers")
+	flag.StringVar(&cfg.sandboxNetworkMode, "sandbox-network-mode", "none", "network mode for sandboxed containers")
+	flag.BoolVar(&cfg.sandboxReadonlyRootfs, "sandbox-readonly-rootfs", true, "mount sandboxed containers' rootfs read-only")
+	flag.StringVar(&cfg.seccompProfile, "seccomp-profile", "seccomp/default.json", "path to the default seccomp profile for sandboxed containers")
 	flag.Parse()
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+
+	registry := runtimes.NewRegistry()
+	if cfg.runtimesConfig != "" {
+		if err := registry.LoadConfig(cfg.runtimesConfig); err != nil {
+			logger.Fatalf("failed to load runtimes config: %v", err)
+		}
+	}
+
+	var seccompProfile string
+	if cfg.seccompProfile != "" {
+		data, err := os.ReadFile(cfg.seccompProfile)
+		if err != nil {
+			logger.Printf("[ERROR] failed to load seccomp profile %s, falling back to the daemon default: %v", cfg.seccompProfile, err)
+		} else {
+			seccompProfile = string(data)
+		}
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithVersion("1.43"))
+	if err != nil {
+		logger.Fatalf("failed to create Docker client: %v", err)
+	}
+
+	byImage := registry.ByImage()
+	var poolImageNames []string
+	if cfg.poolImages != "" {
+		for _, image := range strings.Split(cfg.poolImages, ",") {
+			image = strings.TrimSpace(image)
+			if image != "" {
+				poolImageNames = append(poolImageNames, image)
+			}
+		}
+	} else {
+		for image := range byImage {
+			poolImageNames = append(poolImageNames, image)
+		}
+	}
+
+	var images []pool.ImageConfig
+	for _, image := range poolImageNames {
+		memory := cfg.sandboxMemory
+		nanoCPUs := cfg.sandboxNanoCPUs
+		var workDir string
+		if rt, ok := byImage[image]; ok {
+			workDir = rt.WorkDir()
+			if limits, ok := rt.(runtimes.Limits); ok {
+				if limits.Memory() > 0 {
+					memory = limits.Memory()
+				}
+				if limits.NanoCPUs() > 0 {
+					nanoCPUs = limits.NanoCPUs()
+				}
+			}
+		}
+		images = append(images, pool.ImageConfig{
+			Image:          image,
+			Size:           cfg.poolSize,
+			MaxLifetime:    cfg.poolMaxLifetime,
+			WorkDir:        workDir,
+			Memory:         memory,
+			NanoCPUs:       nanoCPUs,
+			PidsLimit:      cfg.sandboxPidsLimit,
+			NetworkMode:    cfg.sandboxNetworkMode,
+			ReadonlyRootfs: cfg.sandboxReadonlyRootfs,
+			SeccompProfile: seccompProfile,
+		})
+	}
+
+	envManager := pool.NewEnvironmentManager(cli, logger, images)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := envManager.Start(ctx); err != nil {
+		logger.Fatalf("failed to start environment manager: %v", err)
+	}
+
 	app := &application{
-		config: cfg,
-		logger: logger,
+		config:   cfg,
+		logger:   logger,
+		pool:     envManager,
+		runtimes: registry,
 	}
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.port),
@@ -51,7 +168,50 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+
+	shutdownErr := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-quit
+		logger.Printf("caught signal %s, shutting down", sig)
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer shutdownCancel()
+
+		// srv.Shutdown stops new connections but does not wait for hijacked
+		// ones, which every /v1/execute WebSocket is, so it returns almost
+		// immediately regardless of in-flight executions. Wait on inFlight
+		// directly (bounded by the same deadline) before tearing the pool
+		// down, per EnvironmentManager.Shutdown's precondition.
+		srvErr := srv.Shutdown(shutdownCtx)
+
+		drained := make(chan struct{})
+		go func() {
+			app.inFlight.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-shutdownCtx.Done():
+			logger.Printf("[ERROR] timed out waiting for in-flight executions to drain")
+		}
+
+		cancel()
+		if err := envManager.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("[ERROR] environment manager shutdown: %v", err)
+		}
+		shutdownErr <- srvErr
+	}()
+
 	logger.Printf("starting %s server on %s", cfg.env, srv.Addr)
-	err := srv.ListenAndServe()
-	logger.Fatal(err)
+	err = srv.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		logger.Fatal(err)
+	}
+
+	if err := <-shutdownErr; err != nil {
+		logger.Fatal(err)
+	}
+	logger.Print("stopped server")
 }
@@ -6,5 +6,6 @@ func (app *application) routes() *mux.Router {
 	mux := mux.NewRouter()
 	mux.HandleFunc("/v1/execute", app.executeHandler)
 	mux.HandleFunc("/v1/healthcheck", app.healthcheckHandler)
+	mux.HandleFunc("/v1/statistics/environments", app.statisticsHandler)
 	return mux
 }
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// statisticsHandler returns per-image prewarming pool counters.
+func (app *application) statisticsHandler(w http.ResponseWriter, r *http.Request) {
+	env := envelope{"environments": app.pool.StatsAll()}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		app.logger.Printf("[ERROR] Failed to encode statistics: %v", err)
+		app.errorResponse(w, r, http.StatusInternalServerError, "Failed to encode statistics")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/gorilla/websocket"
+)
+
+// execResizer is the subset of *client.Client forwardStdin needs to handle
+// TTY resize messages. Narrowing to an interface lets tests exercise the
+// resize path against a fake instead of a real Docker daemon.
+type execResizer interface {
+	ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error
+}
+
+// clientMessage is the envelope used for every client-sent WebSocket frame
+// that arrives after the initial execute payload: stdin bytes, or a TTY
+// resize when the exec was created with a pseudo-TTY.
+type clientMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Cols uint   `json:"cols"`
+	Rows uint   `json:"rows"`
+}
+
+// forwardStdin turns the client's WebSocket connection into the stdin side
+// of an interactive exec session: "stdin" messages are written to stdin,
+// "resize" messages resize the exec's TTY. It returns once the connection
+// closes or errors, which happens when the client disconnects or the
+// handler's deferred conn.Close runs after the process exits.
+func forwardStdin(ctx context.Context, conn *websocket.Conn, cli execResizer, execID string, stdin io.Writer, logger logFunc) {
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			logger("[ERROR] Invalid client message: %v", err)
+			continue
+		}
+
+		switch msg.Type {
+		case "stdin":
+			if _, err := io.WriteString(stdin, msg.Data); err != nil {
+				logger("[ERROR] Failed to write stdin: %v", err)
+				return
+			}
+		case "resize":
+			if err := cli.ContainerExecResize(ctx, execID, container.ResizeOptions{Height: msg.Rows, Width: msg.Cols}); err != nil {
+				logger("[ERROR] Failed to resize exec TTY: %v", err)
+			}
+		}
+	}
+}
+
+// logFunc matches (*log.Logger).Printf so forwardStdin doesn't need to
+// import the whole application type.
+type logFunc func(format string, v ...interface{})
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/gorilla/websocket"
+)
+
+// fakeExecResizer records every ContainerExecResize call, so tests can
+// assert forwardStdin translated a "resize" message correctly.
+type fakeExecResizer struct {
+	mu    sync.Mutex
+	calls []container.ResizeOptions
+}
+
+func (f *fakeExecResizer) ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, options)
+	return nil
+}
+
+func (f *fakeExecResizer) calledWith() []container.ResizeOptions {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]container.ResizeOptions, len(f.calls))
+	copy(out, f.calls)
+	return out
+}
+
+func dialTestServer(t *testing.T) (server *websocket.Conn, client *websocket.Conn) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	serverConnCh := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("server upgrade: %v", err)
+			return
+		}
+		serverConnCh <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	client, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("client dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	server = <-serverConnCh
+	t.Cleanup(func() { server.Close() })
+	return server, client
+}
+
+func TestForwardStdinWritesStdinMessages(t *testing.T) {
+	server, client := dialTestServer(t)
+	var stdin bytes.Buffer
+	var mu sync.Mutex
+	safeStdin := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return stdin.Write(p)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		forwardStdin(context.Background(), server, &fakeExecResizer{}, "exec1", safeStdin, t.Logf)
+		close(done)
+	}()
+
+	msg, _ := json.Marshal(clientMessage{Type: "stdin", Data: "hello"})
+	if err := client.WriteMessage(websocket.TextMessage, msg); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		got := stdin.String()
+		mu.Unlock()
+		if got == "hello" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stdin never received the forwarded message, got %q", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.Close()
+	<-done
+}
+
+func TestForwardStdinResizesExecTTY(t *testing.T) {
+	server, client := dialTestServer(t)
+	resizer := &fakeExecResizer{}
+
+	done := make(chan struct{})
+	go func() {
+		forwardStdin(context.Background(), server, resizer, "exec1", &bytes.Buffer{}, t.Logf)
+		close(done)
+	}()
+
+	msg, _ := json.Marshal(clientMessage{Type: "resize", Cols: 120, Rows: 40})
+	if err := client.WriteMessage(websocket.TextMessage, msg); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if len(resizer.calledWith()) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("resize was never forwarded to the exec client")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	calls := resizer.calledWith()
+	if calls[0].Width != 120 || calls[0].Height != 40 {
+		t.Fatalf("resize options = %+v, want Width=120 Height=40", calls[0])
+	}
+
+	client.Close()
+	<-done
+}
+
+func TestForwardStdinIgnoresInvalidJSON(t *testing.T) {
+	server, client := dialTestServer(t)
+	resizer := &fakeExecResizer{}
+
+	done := make(chan struct{})
+	go func() {
+		forwardStdin(context.Background(), server, resizer, "exec1", &bytes.Buffer{}, t.Logf)
+		close(done)
+	}()
+
+	if err := client.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	// forwardStdin should log and keep reading rather than returning; a
+	// valid message sent right after should still go through.
+	msg, _ := json.Marshal(clientMessage{Type: "resize", Cols: 1, Rows: 1})
+	if err := client.WriteMessage(websocket.TextMessage, msg); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if len(resizer.calledWith()) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("forwardStdin appears to have stopped reading after invalid JSON")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.Close()
+	<-done
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
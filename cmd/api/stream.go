@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// coalesceWindow bounds how long output sits in a stream's buffer before
+// being flushed to the client as its own WebSocket frame.
+const coalesceWindow = 20 * time.Millisecond
+
+// streamFrame is the tagged JSON envelope sent for every piece of container
+// output and for the terminal exit status. Data is base64-encoded so that
+// container output containing invalid UTF-8 (arbitrary binary output, e.g.
+// a non-text program writing to stdout) isn't corrupted by encoding/json's
+// lossy string handling.
+type streamFrame struct {
+	Stream string `json:"stream"`
+	Data   string `json:"data,omitempty"`
+	Code   int    `json:"code,omitempty"`
+}
+
+// frameWriter serializes writes of streamFrames to a single WebSocket
+// connection; gorilla's Conn is not safe for concurrent writers, and stdout
+// and stderr are flushed from independent goroutines.
+type frameWriter struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (f *frameWriter) send(frame streamFrame) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conn.WriteJSON(frame)
+}
+
+// coalescingWriter buffers one demultiplexed stream and flushes it as a
+// single frame either on a newline or after coalesceWindow elapses, so
+// byte-at-a-time container output doesn't turn into one WebSocket frame per
+// byte.
+type coalescingWriter struct {
+	stream string
+	out    *frameWriter
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+
+	ticker *time.Ticker
+	done   chan struct{}
+}
+
+func newCoalescingWriter(stream string, out *frameWriter) *coalescingWriter {
+	w := &coalescingWriter{
+		stream: stream,
+		out:    out,
+		ticker: time.NewTicker(coalesceWindow),
+		done:   make(chan struct{}),
+	}
+	go w.flushLoop()
+	return w
+}
+
+func (w *coalescingWriter) flushLoop() {
+	for {
+		select {
+		case <-w.ticker.C:
+			w.flush()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Write satisfies io.Writer for use as a stdcopy.StdCopy destination.
+func (w *coalescingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.buf.Write(p)
+	flushNow := bytes.ContainsRune(p, '\n')
+	w.mu.Unlock()
+
+	if flushNow {
+		w.flush()
+	}
+	return len(p), nil
+}
+
+func (w *coalescingWriter) flush() {
+	w.mu.Lock()
+	if w.buf.Len() == 0 {
+		w.mu.Unlock()
+		return
+	}
+	data := base64.StdEncoding.EncodeToString(w.buf.Bytes())
+	w.buf.Reset()
+	w.mu.Unlock()
+
+	_ = w.out.send(streamFrame{Stream: w.stream, Data: data})
+}
+
+// Close stops the flush loop and flushes whatever is left buffered.
+func (w *coalescingWriter) Close() {
+	w.ticker.Stop()
+	close(w.done)
+	w.flush()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestWSPair upgrades an httptest server connection and dials it from a
+// client, returning the server-side and client-side ends of the same
+// WebSocket so tests can write frames on one and read them on the other.
+func newTestWSPair(t *testing.T) (server *websocket.Conn, client *websocket.Conn) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	serverConnCh := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("server upgrade: %v", err)
+			return
+		}
+		serverConnCh <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	client, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("client dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	server = <-serverConnCh
+	t.Cleanup(func() { server.Close() })
+	return server, client
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) streamFrame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame streamFrame
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return frame
+}
+
+func decodeData(t *testing.T, frame streamFrame) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(frame.Data)
+	if err != nil {
+		t.Fatalf("decode frame data: %v", err)
+	}
+	return data
+}
+
+func TestCoalescingWriterFlushesOnNewline(t *testing.T) {
+	server, client := newTestWSPair(t)
+	w := newCoalescingWriter("stdout", &frameWriter{conn: server})
+	defer w.Close()
+
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	frame := readFrame(t, client)
+	if frame.Stream != "stdout" {
+		t.Fatalf("stream = %q, want stdout", frame.Stream)
+	}
+	if got := string(decodeData(t, frame)); got != "hello\n" {
+		t.Fatalf("data = %q, want %q", got, "hello\n")
+	}
+}
+
+func TestCoalescingWriterFlushesOnTicker(t *testing.T) {
+	server, client := newTestWSPair(t)
+	w := newCoalescingWriter("stderr", &frameWriter{conn: server})
+	defer w.Close()
+
+	// No newline, so the only thing that can flush this is the coalesce
+	// ticker.
+	if _, err := w.Write([]byte("partial")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	frame := readFrame(t, client)
+	if got := string(decodeData(t, frame)); got != "partial" {
+		t.Fatalf("data = %q, want %q", got, "partial")
+	}
+}
+
+func TestCoalescingWriterPreservesBinaryData(t *testing.T) {
+	server, client := newTestWSPair(t)
+	w := newCoalescingWriter("stdout", &frameWriter{conn: server})
+
+	binary := []byte{0xff, 0xfe, 'h', 'i', '\n'}
+	if _, err := w.Write(binary); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	w.Close()
+
+	frame := readFrame(t, client)
+	if got := decodeData(t, frame); string(got) != string(binary) {
+		t.Fatalf("binary data corrupted: got %v, want %v", got, binary)
+	}
+}
+
+func TestCoalescingWriterCloseFlushesRemainder(t *testing.T) {
+	server, client := newTestWSPair(t)
+	w := newCoalescingWriter("stdout", &frameWriter{conn: server})
+
+	if _, err := w.Write([]byte("no newline here")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	w.Close()
+
+	frame := readFrame(t, client)
+	if got := string(decodeData(t, frame)); got != "no newline here" {
+		t.Fatalf("data = %q, want %q", got, "no newline here")
+	}
+}
+
+func TestFrameWriterSerializesConcurrentSends(t *testing.T) {
+	server, client := newTestWSPair(t)
+	fw := &frameWriter{conn: server}
+
+	const n = 50
+	done := make(chan struct{}, 2)
+	send := func(stream string) {
+		for i := 0; i < n; i++ {
+			_ = fw.send(streamFrame{Stream: stream, Data: "a"})
+		}
+		done <- struct{}{}
+	}
+	go send("stdout")
+	go send("stderr")
+	<-done
+	<-done
+
+	for i := 0; i < 2*n; i++ {
+		readFrame(t, client)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+
+	"github.com/jelly-afk/sandboxed/runtimes"
+)
+
+// buildTar packs files into a tar archive suitable for CopyToContainer,
+// using each file's path as its tar header name.
+func buildTar(files []runtimes.File) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name: f.Path,
+			Mode: 0644,
+			Size: int64(len(f.Content)),
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/jelly-afk/sandboxed/runtimes"
+)
+
+// maxUploadBytes caps the total decoded size of a submission (loose files or
+// a tarball), so a client can't OOM the API host with one oversized payload
+// before any container resource limit ever applies.
+const maxUploadBytes = 10 * 1024 * 1024
+
+// uploadFile is one entry of the request's "files" field.
+type uploadFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// validatePath rejects anything that could write outside the container's
+// working directory: absolute paths and paths that climb out via "..".
+func validatePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("file path must not be empty")
+	}
+	if strings.ContainsRune(p, 0) {
+		return fmt.Errorf("path %q must not contain a nul byte", p)
+	}
+	if path.IsAbs(p) {
+		return fmt.Errorf("path %q must be relative", p)
+	}
+	clean := path.Clean(p)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("path %q escapes the working directory", p)
+	}
+	return nil
+}
+
+// filesFromUpload turns the request's "files" field into runtime Files,
+// validating every path and the total content size first.
+func filesFromUpload(uploaded []uploadFile) ([]runtimes.File, error) {
+	files := make([]runtimes.File, 0, len(uploaded))
+	var total int
+	for _, f := range uploaded {
+		if err := validatePath(f.Path); err != nil {
+			return nil, err
+		}
+		total += len(f.Content)
+		if total > maxUploadBytes {
+			return nil, fmt.Errorf("upload exceeds maximum size of %d bytes", maxUploadBytes)
+		}
+		files = append(files, runtimes.File{Path: f.Path, Content: []byte(f.Content)})
+	}
+	return files, nil
+}
+
+// tarFromBase64 decodes a base64-encoded tarball and re-packs it after
+// validating every entry, rejecting absolute paths, paths that climb out of
+// the working directory via "..", and symlinks/hardlinks.
+func tarFromBase64(encoded string) (*bytes.Buffer, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 tar: %w", err)
+	}
+	if len(raw) > maxUploadBytes {
+		return nil, fmt.Errorf("tar exceeds maximum size of %d bytes", maxUploadBytes)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(raw))
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeSymlink || hdr.Typeflag == tar.TypeLink {
+			return nil, fmt.Errorf("tar entry %q must not be a link", hdr.Name)
+		}
+		if err := validatePath(hdr.Name); err != nil {
+			return nil, err
+		}
+		total += hdr.Size
+		if total > maxUploadBytes {
+			return nil, fmt.Errorf("tar exceeds maximum size of %d bytes", maxUploadBytes)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(tw, tr, hdr.Size); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"relative file", "main.go", false},
+		{"nested relative file", "pkg/util/helper.go", false},
+		{"empty path", "", true},
+		{"absolute path", "/etc/passwd", true},
+		{"parent traversal", "../../etc/passwd", true},
+		{"parent traversal after clean", "a/../../b", true},
+		{"bare parent", "..", true},
+		{"leading dot but not traversal", "./main.go", false},
+		{"nul byte", "main.go\x00.png", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validatePath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// buildRawTar is a test helper that builds a tar archive without going
+// through buildTar, so tests can include entry types (symlinks, hardlinks)
+// buildTar never produces.
+func buildRawTar(t *testing.T, headers []*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range headers {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write(bytes.Repeat([]byte("a"), int(hdr.Size))); err != nil {
+				t.Fatalf("write content: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestTarFromBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []*tar.Header
+		wantErr bool
+	}{
+		{
+			name: "regular file",
+			headers: []*tar.Header{
+				{Name: "main.go", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+			},
+			wantErr: false,
+		},
+		{
+			name: "nested directory",
+			headers: []*tar.Header{
+				{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0755},
+				{Name: "pkg/util.go", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+			},
+			wantErr: false,
+		},
+		{
+			name: "absolute path",
+			headers: []*tar.Header{
+				{Name: "/etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "parent traversal",
+			headers: []*tar.Header{
+				{Name: "../../etc/passwd", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "symlink rejected",
+			headers: []*tar.Header{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hardlink rejected",
+			headers: []*tar.Header{
+				{Name: "main.go", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+				{Name: "link", Typeflag: tar.TypeLink, Linkname: "main.go"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty entry name",
+			headers: []*tar.Header{
+				{Name: "", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := buildRawTar(t, tt.headers)
+			encoded := base64.StdEncoding.EncodeToString(raw)
+			_, err := tarFromBase64(encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tarFromBase64() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTarFromBase64InvalidBase64(t *testing.T) {
+	if _, err := tarFromBase64("not-valid-base64!!!"); err == nil {
+		t.Fatal("expected an error for invalid base64 input")
+	}
+}
+
+func TestTarFromBase64RejectsOversized(t *testing.T) {
+	headers := []*tar.Header{
+		{Name: "big.bin", Typeflag: tar.TypeReg, Mode: 0644, Size: maxUploadBytes + 1},
+	}
+	raw := buildRawTar(t, headers)
+	encoded := base64.StdEncoding.EncodeToString(raw)
+	_, err := tarFromBase64(encoded)
+	if err == nil {
+		t.Fatal("expected an error for a tar exceeding maxUploadBytes")
+	}
+	if !strings.Contains(err.Error(), "exceeds maximum size") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
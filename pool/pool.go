@@ -0,0 +1,392 @@
+// Package pool maintains prewarmed runtime containers so that
+// /v1/execute doesn't pay the full container-create + copy + start cost on
+// every request. It follows Poseidon's environment-manager design: a
+// bounded, goroutine-safe set of idle "warm" containers per runtime image
+// that requests acquire and release, refilled asynchronously in the
+// background.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Label is set on every container the pool creates so it can recognize its
+// own containers again after a Docker daemon restart.
+const Label = "sandboxed.pool"
+
+// dockerClient is the subset of *client.Client the pool needs. Narrowing to
+// an interface (rather than depending on *client.Client directly) lets
+// tests exercise the pool's lifecycle logic against a fake Docker daemon.
+type dockerClient interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error)
+	ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error
+	ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error
+}
+
+// ImageConfig controls how many warm containers are kept ready for a given
+// image, how long a single container is allowed to live before it is
+// recycled regardless of use, and the sandbox it runs under.
+type ImageConfig struct {
+	Image       string
+	Size        int
+	MaxLifetime time.Duration
+
+	// WorkDir is given a writable tmpfs mount so runtimes can still write
+	// their source files under an otherwise read-only rootfs.
+	WorkDir string
+
+	Memory         int64
+	MemorySwap     int64
+	NanoCPUs       int64
+	PidsLimit      int64
+	NetworkMode    string
+	ReadonlyRootfs bool
+	// SeccompProfile is the raw JSON of a seccomp profile, embedded
+	// directly into HostConfig.SecurityOpt. Empty leaves the daemon's
+	// default profile in place.
+	SeccompProfile string
+}
+
+// Stats is the point-in-time snapshot returned by /v1/statistics/environments.
+type Stats struct {
+	PrewarmingPoolSize int `json:"prewarming_pool_size"`
+	IdleRunners        int `json:"idle_runners"`
+	UsedRunners        int `json:"used_runners"`
+}
+
+// runner is a single warm container tracked by the pool.
+type runner struct {
+	id      string
+	created time.Time
+}
+
+// imagePool is the per-image bookkeeping: idle runner IDs plus a bounded
+// semaphore so at most Size runners are ever outstanding (idle + in use) at
+// once.
+type imagePool struct {
+	cfg  ImageConfig
+	sem  chan struct{}
+	mu   sync.Mutex
+	idle []*runner
+	used int
+}
+
+// EnvironmentManager owns one imagePool per configured runtime image.
+type EnvironmentManager struct {
+	cli    dockerClient
+	logger *log.Logger
+
+	mu    sync.RWMutex
+	pools map[string]*imagePool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEnvironmentManager builds a manager for the given images. It does not
+// talk to Docker until Start is called.
+func NewEnvironmentManager(cli dockerClient, logger *log.Logger, configs []ImageConfig) *EnvironmentManager {
+	pools := make(map[string]*imagePool, len(configs))
+	for _, cfg := range configs {
+		pools[cfg.Image] = &imagePool{
+			cfg: cfg,
+			sem: make(chan struct{}, cfg.Size),
+		}
+	}
+	return &EnvironmentManager{
+		cli:    cli,
+		logger: logger,
+		pools:  pools,
+	}
+}
+
+// Start reconciles with any pool containers left over from a previous
+// process (labeled sandboxed.pool=<image>) and then tops every pool up to
+// its configured size. Background fills run under a context derived from
+// ctx, which Shutdown cancels.
+func (m *EnvironmentManager) Start(ctx context.Context) error {
+	m.ctx, m.cancel = context.WithCancel(ctx)
+
+	for image, p := range m.pools {
+		if err := m.reconcile(m.ctx, image, p); err != nil {
+			return fmt.Errorf("pool: reconcile %s: %w", image, err)
+		}
+		m.fillAsync(image, p)
+	}
+	return nil
+}
+
+// reconcile lists containers left behind by a previous run and adopts them
+// as idle runners (up to the configured size), removing anything extra.
+// Containers that came back from a daemon or host restart in anything but
+// the running state (these are started bare, with no restart policy) are
+// dead weight, not idle capacity, so they're removed rather than trusted.
+func (m *EnvironmentManager) reconcile(ctx context.Context, image string, p *imagePool) error {
+	args := filters.NewArgs()
+	args.Add("label", fmt.Sprintf("%s=%s", Label, image))
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range containers {
+		if c.State != "running" {
+			m.logger.Printf("[INFO] pool: removing non-running %s runner %s (state %s)", image, shortID(c.ID), c.State)
+			_ = m.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true})
+			continue
+		}
+		if len(p.idle) >= p.cfg.Size {
+			m.logger.Printf("[INFO] pool: removing surplus %s runner %s", image, shortID(c.ID))
+			_ = m.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true})
+			continue
+		}
+		p.idle = append(p.idle, &runner{id: c.ID, created: time.Unix(c.Created, 0)})
+		p.sem <- struct{}{}
+	}
+	m.logger.Printf("[INFO] pool: reconciled %d/%d %s runners", len(p.idle), p.cfg.Size, image)
+	return nil
+}
+
+// fillAsync tops a pool up to its configured size in the background.
+func (m *EnvironmentManager) fillAsync(image string, p *imagePool) {
+	p.mu.Lock()
+	missing := p.cfg.Size - len(p.idle) - p.used
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.spawn(m.ctx, image, p); err != nil {
+				m.logger.Printf("[ERROR] pool: failed to spawn %s runner: %v", image, err)
+			}
+		}()
+	}
+}
+
+// hostConfig builds the sandboxed HostConfig every container in this pool
+// is created with: the pool's network mode and read-only rootfs setting,
+// writable tmpfs mounts for /tmp and the working directory, every
+// capability dropped, and the pool's resource limits and seccomp profile.
+func (p *imagePool) hostConfig() *container.HostConfig {
+	workDir := p.cfg.WorkDir
+	if workDir == "" {
+		workDir = "/"
+	}
+
+	securityOpt := []string{"no-new-privileges"}
+	if p.cfg.SeccompProfile != "" {
+		securityOpt = append(securityOpt, "seccomp="+p.cfg.SeccompProfile)
+	}
+
+	var pidsLimit *int64
+	if p.cfg.PidsLimit > 0 {
+		pidsLimit = &p.cfg.PidsLimit
+	}
+
+	return &container.HostConfig{
+		Resources: container.Resources{
+			Memory:     p.cfg.Memory,
+			MemorySwap: p.cfg.MemorySwap,
+			NanoCPUs:   p.cfg.NanoCPUs,
+			PidsLimit:  pidsLimit,
+		},
+		NetworkMode:    container.NetworkMode(p.cfg.NetworkMode),
+		ReadonlyRootfs: p.cfg.ReadonlyRootfs,
+		SecurityOpt:    securityOpt,
+		CapDrop:        []string{"ALL"},
+		Tmpfs: map[string]string{
+			"/tmp":  "rw,noexec,nosuid,size=64m",
+			workDir: "rw,nosuid,size=64m",
+		},
+	}
+}
+
+// spawn creates one new warm container for image and adds it to the idle
+// set. It blocks on the pool's semaphore so the pool never exceeds its
+// configured size.
+func (m *EnvironmentManager) spawn(ctx context.Context, image string, p *imagePool) error {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	resp, err := m.cli.ContainerCreate(ctx, &container.Config{
+		Image:      image,
+		Entrypoint: []string{"sleep", "infinity"},
+		Labels:     map[string]string{Label: image},
+	}, p.hostConfig(), nil, nil, "")
+	if err != nil {
+		<-p.sem
+		return err
+	}
+	if err := m.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		<-p.sem
+		_ = m.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		return err
+	}
+
+	p.mu.Lock()
+	p.idle = append(p.idle, &runner{id: resp.ID, created: time.Now()})
+	p.mu.Unlock()
+	return nil
+}
+
+// Acquire removes one idle runner from image's pool and hands it to the
+// caller. It blocks until a runner is available or ctx is cancelled. Idle
+// runners older than the pool's MaxLifetime are retired and replaced rather
+// than handed out.
+func (m *EnvironmentManager) Acquire(ctx context.Context, image string) (string, error) {
+	m.mu.RLock()
+	p, ok := m.pools[image]
+	m.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("pool: no pool configured for image %q", image)
+	}
+
+	for {
+		p.mu.Lock()
+		for len(p.idle) > 0 && p.expired(p.idle[0]) {
+			expired := p.idle[0]
+			p.idle = p.idle[1:]
+			p.mu.Unlock()
+			m.retire(image, p, expired)
+			p.mu.Lock()
+		}
+		if len(p.idle) > 0 {
+			r := p.idle[0]
+			p.idle = p.idle[1:]
+			p.used++
+			p.mu.Unlock()
+			return r.id, nil
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(25 * time.Millisecond):
+		}
+	}
+}
+
+// expired reports whether r has outlived the pool's MaxLifetime. A
+// MaxLifetime of zero disables the check.
+func (p *imagePool) expired(r *runner) bool {
+	return p.cfg.MaxLifetime > 0 && time.Since(r.created) >= p.cfg.MaxLifetime
+}
+
+// shortID trims a container ID to its usual 12-character log prefix,
+// tolerating shorter IDs (e.g. from a test double) instead of panicking.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// retire removes an idle runner that outlived MaxLifetime and spawns its
+// replacement in the background. The runner must already be out of p.idle.
+func (m *EnvironmentManager) retire(image string, p *imagePool, r *runner) {
+	m.logger.Printf("[INFO] pool: retiring %s runner %s, exceeded max lifetime", image, shortID(r.id))
+	if err := m.cli.ContainerRemove(m.ctx, r.id, container.RemoveOptions{Force: true}); err != nil {
+		m.logger.Printf("[ERROR] pool: failed to remove expired runner %s: %v", r.id, err)
+	}
+	<-p.sem
+	m.fillAsync(image, p)
+}
+
+// Release destroys the runner a caller acquired and asynchronously spawns a
+// replacement so the pool stays at its target size.
+func (m *EnvironmentManager) Release(image, id string) {
+	m.mu.RLock()
+	p, ok := m.pools[image]
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	p.used--
+	p.mu.Unlock()
+	<-p.sem
+
+	if err := m.cli.ContainerRemove(m.ctx, id, container.RemoveOptions{Force: true}); err != nil {
+		m.logger.Printf("[ERROR] pool: failed to remove used runner %s: %v", id, err)
+	}
+	m.fillAsync(image, p)
+}
+
+// Stats reports the current size of the named image's pool.
+func (m *EnvironmentManager) Stats(image string) (Stats, bool) {
+	m.mu.RLock()
+	p, ok := m.pools[image]
+	m.mu.RUnlock()
+	if !ok {
+		return Stats{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Stats{
+		PrewarmingPoolSize: p.cfg.Size,
+		IdleRunners:        len(p.idle),
+		UsedRunners:        p.used,
+	}, true
+}
+
+// StatsAll reports stats for every configured image, keyed by image name.
+func (m *EnvironmentManager) StatsAll() map[string]Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]Stats, len(m.pools))
+	for image, p := range m.pools {
+		p.mu.Lock()
+		out[image] = Stats{
+			PrewarmingPoolSize: p.cfg.Size,
+			IdleRunners:        len(p.idle),
+			UsedRunners:        p.used,
+		}
+		p.mu.Unlock()
+	}
+	return out
+}
+
+// Shutdown cancels any in-flight fills and removes every idle runner still
+// tracked by the manager. It does not touch runners currently on loan to a
+// request; callers are expected to have drained those first.
+func (m *EnvironmentManager) Shutdown(ctx context.Context) error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for image, p := range m.pools {
+		p.mu.Lock()
+		idle := p.idle
+		p.idle = nil
+		p.mu.Unlock()
+		for _, r := range idle {
+			if err := m.cli.ContainerRemove(ctx, r.id, container.RemoveOptions{Force: true}); err != nil {
+				m.logger.Printf("[ERROR] pool: failed to remove %s runner %s during shutdown: %v", image, r.id, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// fakeDockerClient is a minimal in-memory stand-in for *client.Client that
+// implements dockerClient, so the pool's lifecycle logic can be tested
+// without a real Docker daemon.
+type fakeDockerClient struct {
+	mu       sync.Mutex
+	existing []types.Container
+	nextID   int
+	removed  []string
+}
+
+func (f *fakeDockerClient) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]types.Container, len(f.existing))
+	copy(out, f.existing)
+	return out, nil
+}
+
+func (f *fakeDockerClient) ContainerCreate(ctx context.Context, cfg *container.Config, hostCfg *container.HostConfig, _ *network.NetworkingConfig, _ *ocispec.Platform, _ string) (container.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	return container.CreateResponse{ID: fmt.Sprintf("spawned-%d", f.nextID)}, nil
+}
+
+func (f *fakeDockerClient) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	return nil
+}
+
+func (f *fakeDockerClient) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+func (f *fakeDockerClient) removedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.removed))
+	copy(out, f.removed)
+	return out
+}
+
+func testLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestImagePoolExpired(t *testing.T) {
+	p := &imagePool{cfg: ImageConfig{MaxLifetime: time.Minute}}
+
+	fresh := &runner{created: time.Now()}
+	if p.expired(fresh) {
+		t.Fatal("a freshly created runner should not be expired")
+	}
+
+	stale := &runner{created: time.Now().Add(-2 * time.Minute)}
+	if !p.expired(stale) {
+		t.Fatal("a runner older than MaxLifetime should be expired")
+	}
+
+	p.cfg.MaxLifetime = 0
+	if p.expired(stale) {
+		t.Fatal("MaxLifetime of 0 should disable expiry")
+	}
+}
+
+func TestAcquireRelease(t *testing.T) {
+	fake := &fakeDockerClient{}
+	mgr := NewEnvironmentManager(fake, testLogger(), []ImageConfig{{Image: "img", Size: 1}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer mgr.Shutdown(context.Background())
+
+	id, err := mgr.Acquire(ctx, "img")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if id == "" {
+		t.Fatal("expected a non-empty runner id")
+	}
+
+	if stats, ok := mgr.Stats("img"); !ok || stats.UsedRunners != 1 || stats.IdleRunners != 0 {
+		t.Fatalf("unexpected stats after Acquire: %+v (ok=%v)", stats, ok)
+	}
+
+	mgr.Release("img", id)
+
+	// Release removes the runner and spawns its replacement in the
+	// background, so poll for the pool to refill.
+	deadline := time.Now().Add(time.Second)
+	var stats Stats
+	for {
+		stats, _ = mgr.Stats("img")
+		if stats.IdleRunners == 1 && stats.UsedRunners == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool did not refill after Release: %+v", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	removed := fake.removedIDs()
+	if len(removed) != 1 || removed[0] != id {
+		t.Fatalf("expected Release to remove %q, removed = %v", id, removed)
+	}
+}
+
+func TestAcquireRetiresExpiredRunners(t *testing.T) {
+	fake := &fakeDockerClient{}
+	mgr := NewEnvironmentManager(fake, testLogger(), []ImageConfig{
+		{Image: "img", Size: 1, MaxLifetime: time.Minute},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer mgr.Shutdown(context.Background())
+
+	// Wait for the initial fill, then age the idle runner directly rather
+	// than sleeping past a real MaxLifetime.
+	p := mgr.pools["img"]
+	var staleID string
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 1 {
+			staleID = p.idle[0].id
+			p.idle[0].created = time.Now().Add(-time.Hour)
+			p.mu.Unlock()
+			break
+		}
+		p.mu.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatal("pool never filled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	id, err := mgr.Acquire(ctx, "img")
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if id == staleID {
+		t.Fatal("expected Acquire to retire the expired runner instead of handing it out")
+	}
+
+	removed := fake.removedIDs()
+	var foundStale bool
+	for _, r := range removed {
+		if r == staleID {
+			foundStale = true
+		}
+	}
+	if !foundStale {
+		t.Fatalf("expected expired runner %q to be removed, removed = %v", staleID, removed)
+	}
+}
+
+func TestReconcileDiscardsNonRunning(t *testing.T) {
+	now := time.Now().Unix()
+	fake := &fakeDockerClient{existing: []types.Container{
+		{ID: "running-1", State: "running", Created: now},
+		{ID: "exited-1", State: "exited", Created: now},
+	}}
+	mgr := NewEnvironmentManager(fake, testLogger(), []ImageConfig{{Image: "img", Size: 2}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := mgr.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer mgr.Shutdown(context.Background())
+
+	removed := fake.removedIDs()
+	var foundExited bool
+	for _, r := range removed {
+		if r == "exited-1" {
+			foundExited = true
+		}
+	}
+	if !foundExited {
+		t.Fatalf("expected exited-1 to be removed during reconcile, removed = %v", removed)
+	}
+
+	p := mgr.pools["img"]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.idle {
+		if r.id == "exited-1" {
+			t.Fatal("exited-1 should not have been adopted as an idle runner")
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package runtimes
+
+import "time"
+
+// defaultTimeout is used by every built-in runtime; it can be overridden
+// per-language by registering a config-driven runtime instead.
+const defaultTimeout = 30 * time.Second
+
+// goRuntime runs a single main.go with `go run`.
+type goRuntime struct{}
+
+func (goRuntime) Image() string   { return "golang:1.21" }
+func (goRuntime) WorkDir() string { return "/app" }
+func (goRuntime) Files(input Payload) []File {
+	return []File{{Path: "main.go", Content: []byte(input.Text)}}
+}
+func (goRuntime) Command() []string      { return []string{"go", "run", "main.go"} }
+func (goRuntime) Timeout() time.Duration { return defaultTimeout }
+
+// pythonRuntime runs a single main.py with the CPython interpreter.
+type pythonRuntime struct{}
+
+func (pythonRuntime) Image() string   { return "python:3.11" }
+func (pythonRuntime) WorkDir() string { return "/app" }
+func (pythonRuntime) Files(input Payload) []File {
+	return []File{{Path: "main.py", Content: []byte(input.Text)}}
+}
+func (pythonRuntime) Command() []string      { return []string{"python", "main.py"} }
+func (pythonRuntime) Timeout() time.Duration { return defaultTimeout }
+
+// nodeRuntime runs a single index.js with Node.
+type nodeRuntime struct{}
+
+func (nodeRuntime) Image() string   { return "node:20" }
+func (nodeRuntime) WorkDir() string { return "/app" }
+func (nodeRuntime) Files(input Payload) []File {
+	return []File{{Path: "index.js", Content: []byte(input.Text)}}
+}
+func (nodeRuntime) Command() []string      { return []string{"node", "index.js"} }
+func (nodeRuntime) Timeout() time.Duration { return defaultTimeout }
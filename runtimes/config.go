@@ -0,0 +1,106 @@
+package runtimes
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the top-level shape of the YAML runtimes file passed via
+// -runtimes-config.
+type fileConfig struct {
+	Runtimes []runtimeConfig `yaml:"runtimes"`
+}
+
+// runtimeConfig describes one additional, non-built-in runtime. Entrypoint
+// elements are text/template strings rendered against templateData, which
+// lets a single config entry place the submitted source under any
+// filename the language expects (e.g. "main.rs").
+type runtimeConfig struct {
+	Language   string        `yaml:"language"`
+	Image      string        `yaml:"image"`
+	WorkDir    string        `yaml:"workdir"`
+	Filename   string        `yaml:"filename"`
+	Entrypoint []string      `yaml:"entrypoint"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Memory     int64         `yaml:"memory"`
+	NanoCPUs   int64         `yaml:"nano_cpus"`
+}
+
+// templateData is exposed to a runtimeConfig's Entrypoint templates.
+type templateData struct {
+	Filename string
+}
+
+// configRuntime adapts a runtimeConfig loaded from YAML to the Runtime
+// interface.
+type configRuntime struct {
+	cfg     runtimeConfig
+	command []string
+}
+
+func (c configRuntime) Image() string   { return c.cfg.Image }
+func (c configRuntime) WorkDir() string { return c.cfg.WorkDir }
+
+func (c configRuntime) Files(input Payload) []File {
+	return []File{{Path: c.cfg.Filename, Content: []byte(input.Text)}}
+}
+
+func (c configRuntime) Command() []string { return c.command }
+
+func (c configRuntime) Timeout() time.Duration {
+	if c.cfg.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return c.cfg.Timeout
+}
+
+// Memory and NanoCPUs implement Limits, letting a YAML runtimes config
+// override the server's default sandbox resource limits for the runtime's
+// image. Limits apply per pooled image, so where several runtimes share an
+// image only one of them supplies the limits.
+func (c configRuntime) Memory() int64   { return c.cfg.Memory }
+func (c configRuntime) NanoCPUs() int64 { return c.cfg.NanoCPUs }
+
+// LoadConfig reads a YAML runtimes file and registers every entry in it,
+// rendering each Entrypoint element against the runtime's own filename.
+func (r *Registry) LoadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("runtimes: read config: %w", err)
+	}
+
+	var cfg fileConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("runtimes: parse config: %w", err)
+	}
+
+	for _, rc := range cfg.Runtimes {
+		command, err := renderCommand(rc.Entrypoint, templateData{Filename: rc.Filename})
+		if err != nil {
+			return fmt.Errorf("runtimes: render entrypoint for %q: %w", rc.Language, err)
+		}
+		r.Register(rc.Language, configRuntime{cfg: rc, command: command})
+	}
+	return nil
+}
+
+func renderCommand(entrypoint []string, data templateData) ([]string, error) {
+	command := make([]string, len(entrypoint))
+	for i, part := range entrypoint {
+		tmpl, err := template.New("entrypoint").Parse(part)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		command[i] = buf.String()
+	}
+	return command, nil
+}
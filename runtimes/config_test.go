@@ -0,0 +1,120 @@
+package runtimes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfigYAML = `
+runtimes:
+  - language: rust
+    image: rust:1.75
+    workdir: /app
+    filename: main.rs
+    entrypoint: ["rustc", "{{.Filename}}", "-o", "main", "&&", "./main"]
+    timeout: 5s
+    memory: 134217728
+    nano_cpus: 500000000
+  - language: bash
+    image: bash:5
+    workdir: /app
+    filename: script.sh
+    entrypoint: ["bash", "{{.Filename}}"]
+`
+
+func writeTestConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "runtimes.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0644); err != nil {
+		t.Fatalf("writing test config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigRegistersRuntimes(t *testing.T) {
+	r := NewRegistry()
+	if err := r.LoadConfig(writeTestConfig(t)); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	rt, err := r.Lookup("rust")
+	if err != nil {
+		t.Fatalf("Lookup(rust): %v", err)
+	}
+	if rt.Image() != "rust:1.75" {
+		t.Fatalf("Image() = %q, want rust:1.75", rt.Image())
+	}
+	if rt.WorkDir() != "/app" {
+		t.Fatalf("WorkDir() = %q, want /app", rt.WorkDir())
+	}
+	if rt.Timeout() != 5*time.Second {
+		t.Fatalf("Timeout() = %v, want 5s", rt.Timeout())
+	}
+
+	files := rt.Files(Payload{Text: "fn main() {}"})
+	if len(files) != 1 || files[0].Path != "main.rs" || string(files[0].Content) != "fn main() {}" {
+		t.Fatalf("Files() = %+v, unexpected", files)
+	}
+
+	wantCommand := []string{"rustc", "main.rs", "-o", "main", "&&", "./main"}
+	gotCommand := rt.Command()
+	if len(gotCommand) != len(wantCommand) {
+		t.Fatalf("Command() = %v, want %v", gotCommand, wantCommand)
+	}
+	for i := range wantCommand {
+		if gotCommand[i] != wantCommand[i] {
+			t.Fatalf("Command()[%d] = %q, want %q", i, gotCommand[i], wantCommand[i])
+		}
+	}
+
+	limits, ok := rt.(Limits)
+	if !ok {
+		t.Fatal("config-loaded runtime should implement Limits")
+	}
+	if limits.Memory() != 128*1024*1024 {
+		t.Fatalf("Memory() = %d, want 128MiB", limits.Memory())
+	}
+	if limits.NanoCPUs() != 500_000_000 {
+		t.Fatalf("NanoCPUs() = %d, want 500000000", limits.NanoCPUs())
+	}
+}
+
+func TestLoadConfigDefaultTimeout(t *testing.T) {
+	r := NewRegistry()
+	if err := r.LoadConfig(writeTestConfig(t)); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	rt, err := r.Lookup("bash")
+	if err != nil {
+		t.Fatalf("Lookup(bash): %v", err)
+	}
+	if rt.Timeout() != defaultTimeout {
+		t.Fatalf("Timeout() = %v, want the package default %v", rt.Timeout(), defaultTimeout)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	r := NewRegistry()
+	if err := r.LoadConfig(filepath.Join(t.TempDir(), "does-not-exist.yaml")); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+}
+
+func TestRenderCommandTemplating(t *testing.T) {
+	command, err := renderCommand([]string{"run", "{{.Filename}}", "--name={{.Filename}}"}, templateData{Filename: "main.go"})
+	if err != nil {
+		t.Fatalf("renderCommand: %v", err)
+	}
+	want := []string{"run", "main.go", "--name=main.go"}
+	if len(command) != len(want) {
+		t.Fatalf("renderCommand = %v, want %v", command, want)
+	}
+	for i := range want {
+		if command[i] != want[i] {
+			t.Fatalf("renderCommand[%d] = %q, want %q", i, command[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package runtimes
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry looks runtimes up by the "language" field of an execute request.
+// It is safe for concurrent use: Register may run while lookups are in
+// flight.
+type Registry struct {
+	mu       sync.RWMutex
+	runtimes map[string]Runtime
+}
+
+// NewRegistry builds a registry pre-populated with the built-in Go, Python
+// and Node runtimes.
+func NewRegistry() *Registry {
+	r := &Registry{runtimes: make(map[string]Runtime)}
+	r.Register("go", goRuntime{})
+	r.Register("python", pythonRuntime{})
+	r.Register("node", nodeRuntime{})
+	return r
+}
+
+// Register adds or replaces the runtime for language.
+func (r *Registry) Register(language string, rt Runtime) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runtimes[language] = rt
+}
+
+// Lookup returns the runtime registered for language, if any.
+func (r *Registry) Lookup(language string) (Runtime, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rt, ok := r.runtimes[language]
+	if !ok {
+		return nil, fmt.Errorf("runtimes: no runtime registered for language %q", language)
+	}
+	return rt, nil
+}
+
+// ByImage returns one registered runtime per distinct image, used to size
+// and sandbox the prewarmed pool for each one.
+func (r *Registry) ByImage() map[string]Runtime {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	byImage := make(map[string]Runtime)
+	for _, rt := range r.runtimes {
+		if _, ok := byImage[rt.Image()]; !ok {
+			byImage[rt.Image()] = rt
+		}
+	}
+	return byImage
+}
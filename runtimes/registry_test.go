@@ -0,0 +1,59 @@
+package runtimes
+
+import "testing"
+
+func TestNewRegistryRegistersBuiltins(t *testing.T) {
+	r := NewRegistry()
+
+	for _, tt := range []struct {
+		language string
+		image    string
+	}{
+		{"go", "golang:1.21"},
+		{"python", "python:3.11"},
+		{"node", "node:20"},
+	} {
+		rt, err := r.Lookup(tt.language)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", tt.language, err)
+		}
+		if rt.Image() != tt.image {
+			t.Fatalf("Lookup(%q).Image() = %q, want %q", tt.language, rt.Image(), tt.image)
+		}
+	}
+}
+
+func TestLookupUnknownLanguage(t *testing.T) {
+	r := NewRegistry()
+	if _, err := r.Lookup("cobol"); err == nil {
+		t.Fatal("expected an error for an unregistered language")
+	}
+}
+
+func TestRegisterOverridesExisting(t *testing.T) {
+	r := NewRegistry()
+	r.Register("go", pythonRuntime{})
+
+	rt, err := r.Lookup("go")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if rt.Image() != "python:3.11" {
+		t.Fatalf("Register did not override the built-in go runtime, got image %q", rt.Image())
+	}
+}
+
+func TestByImageDedupes(t *testing.T) {
+	r := NewRegistry()
+	r.Register("go2", goRuntime{})
+
+	byImage := r.ByImage()
+	if len(byImage) != 3 {
+		t.Fatalf("ByImage() returned %d images, want 3 (go and go2 share golang:1.21)", len(byImage))
+	}
+	for image, rt := range byImage {
+		if rt.Image() != image {
+			t.Fatalf("ByImage()[%q].Image() = %q, want %q", image, rt.Image(), image)
+		}
+	}
+}
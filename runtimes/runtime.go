@@ -0,0 +1,46 @@
+// Package runtimes decouples executeHandler from any one language: each
+// supported language is a Runtime that knows its own image, working
+// directory, source layout and run command, looked up from the request's
+// "language" field through a Registry.
+package runtimes
+
+import "time"
+
+// File is a single source file to be written into the execution container,
+// relative to a Runtime's WorkDir.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Payload is the subset of the WebSocket request needed to build a
+// runtime's file tree. Text is the single-file submission the API has
+// always accepted.
+type Payload struct {
+	Text string
+}
+
+// Limits is implemented by runtimes that carry their own resource-limit
+// overrides, such as those loaded from the YAML runtimes config. Runtimes
+// that don't implement it run under the server's default sandbox limits.
+type Limits interface {
+	Memory() int64
+	NanoCPUs() int64
+}
+
+// Runtime describes how to run one language inside a sandbox container.
+type Runtime interface {
+	// Image is the Docker image this runtime executes in.
+	Image() string
+	// WorkDir is the directory the source files are copied into and the
+	// command is run from.
+	WorkDir() string
+	// Files renders the payload into the files that should be copied into
+	// WorkDir before Command runs.
+	Files(input Payload) []File
+	// Command is the argv used to build and/or run the submitted code.
+	Command() []string
+	// Timeout bounds how long a single execution of this runtime is
+	// allowed to run before it is killed.
+	Timeout() time.Duration
+}